@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,11 +16,19 @@ import (
 	goversion "github.com/caarlos0/go-version"
 
 	"github.com/hugginsio/skopos/internal/config"
+	"github.com/hugginsio/skopos/internal/dnsforwarder"
+	"github.com/hugginsio/skopos/internal/healthcheck"
+	"github.com/hugginsio/skopos/internal/metrics"
+	"github.com/hugginsio/skopos/internal/rpc"
 	"github.com/hugginsio/skopos/internal/tailscale"
 )
 
+// metricsPort is the port the Prometheus /metrics endpoint is served on, on
+// the node's Tailscale IPv4 address.
+const metricsPort = "9090"
+
 func main() {
-	cfg, err := config.Load()
+	cfg, err := config.Load("skopos-dnsmon")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
 		os.Exit(5)
@@ -44,11 +54,16 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 
 	ts, err := tailscale.New(tailscale.Config{
-		Hostname:  cfg.Tailscale.Hostname,
-		StateDir:  cfg.Tailscale.StateDir,
-		AuthKey:   cfg.Tailscale.AuthKey,
-		Logger:    logger,
-		Ephemeral: false,
+		Hostname:     cfg.Tailscale.Hostname,
+		StateDir:     cfg.Tailscale.StateDir,
+		AuthKey:      cfg.Tailscale.AuthKey,
+		Logger:       logger,
+		Ephemeral:    cfg.Tailscale.Ephemeral,
+		Routes:       cfg.Tailscale.Routes,
+		AcceptRoutes: cfg.Tailscale.AcceptRoutes,
+		Tags:         cfg.Tailscale.Tags,
+		Userspace:    cfg.Tailscale.Userspace,
+		ExtraArgs:    cfg.Tailscale.ExtraArgs,
 	})
 
 	if err != nil {
@@ -61,9 +76,95 @@ func main() {
 		os.Exit(5)
 	}
 
-	// TODO: Initialize RPC server
-	// TODO: Initialize health checker
-	// TODO: Initialize DNS config syncer
+	reg := metrics.New(ts.Health())
+
+	var checker *healthcheck.Checker
+	if len(cfg.HealthCheck.Targets) > 0 {
+		targets := make([]healthcheck.TargetSpec, len(cfg.HealthCheck.Targets))
+		for i, t := range cfg.HealthCheck.Targets {
+			targets[i] = healthcheck.TargetSpec{IP: t.IP, FQDN: t.FQDN, ResolveInterval: t.ResolveInterval}
+		}
+
+		checker, err = healthcheck.New(ts, healthcheck.Config{
+			Targets:            targets,
+			Interval:           cfg.HealthCheck.Interval,
+			Timeout:            cfg.HealthCheck.Timeout,
+			UnhealthyThreshold: cfg.HealthCheck.UnhealthyThreshold,
+			Logger:             logger,
+			Recorder:           reg,
+		})
+		if err != nil {
+			logger.Error("failed to create health checker", "error", err)
+			os.Exit(5)
+		}
+
+		if err := checker.Start(ctx); err != nil {
+			logger.Error("failed to start health checker", "error", err)
+			os.Exit(5)
+		}
+	} else {
+		logger.Warn("no health check targets configured (HEALTH_CHECK_TARGETS/HEALTH_CHECK_FQDN_TARGETS), health checker disabled")
+	}
+
+	var rpcLn, watchLn net.Listener
+	if checker != nil {
+		rpcSrv := rpc.NewServer(&healthSource{checker: checker}, ts.LocalClient(), logger)
+
+		rpcLn, err = ts.Listen("tcp4", net.JoinHostPort(ts.TailscaleIP(), rpc.DefaultRPCPort))
+		if err != nil {
+			logger.Error("failed to start RPC listener", "error", err)
+			os.Exit(5)
+		}
+
+		watchLn, err = ts.Listen("tcp4", net.JoinHostPort(ts.TailscaleIP(), rpc.DefaultWatchPort))
+		if err != nil {
+			logger.Error("failed to start watch listener", "error", err)
+			os.Exit(5)
+		}
+
+		go func() {
+			if err := rpcSrv.ServeRPC(rpcLn); err != nil {
+				logger.Debug("RPC server stopped", "error", err)
+			}
+		}()
+
+		go func() {
+			if err := rpcSrv.ServeWatch(watchLn); err != nil {
+				logger.Debug("watch server stopped", "error", err)
+			}
+		}()
+	} else {
+		logger.Warn("RPC server disabled, no health checker to serve")
+	}
+
+	fwd, err := dnsforwarder.New(ts, dnsforwarder.Config{
+		Upstreams: cfg.DNS.Upstreams,
+		Timeout:   cfg.DNS.UpdateTimeout,
+		Logger:    logger,
+		Recorder:  reg,
+	})
+	if err != nil {
+		logger.Error("failed to create DNS forwarder", "error", err)
+		os.Exit(5)
+	}
+
+	if err := fwd.Start(ctx); err != nil {
+		logger.Error("failed to start DNS forwarder", "error", err)
+		os.Exit(5)
+	}
+
+	metricsLn, err := listenMetrics(ts, metricsPort)
+	if err != nil {
+		logger.Error("failed to start metrics listener", "error", err)
+		os.Exit(5)
+	}
+
+	metricsSrv := &http.Server{Handler: reg.Handler()}
+	go func() {
+		if err := metricsSrv.Serve(metricsLn); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server failed", "error", err)
+		}
+	}()
 
 	logger.Info("dnsmon OK")
 
@@ -71,9 +172,26 @@ func main() {
 	sig := <-sigChan
 	logger.Info("received shutdown signal", "signal", sig)
 
-	// TODO: Stop health checker
-	// TODO: Stop DNS config syncer
-	// TODO: Stop RPC server
+	if err := metricsSrv.Close(); err != nil {
+		logger.Error("error closing metrics server", "error", err)
+	}
+
+	if rpcLn != nil {
+		rpcLn.Close()
+	}
+	if watchLn != nil {
+		watchLn.Close()
+	}
+
+	if checker != nil {
+		if err := checker.Close(); err != nil {
+			logger.Error("error closing health checker", "error", err)
+		}
+	}
+
+	if err := fwd.Close(); err != nil {
+		logger.Error("error closing DNS forwarder", "error", err)
+	}
 
 	if err := ts.Close(); err != nil {
 		logger.Error("error closing Tailscale server", "error", err)
@@ -81,3 +199,14 @@ func main() {
 
 	logger.Info("goodbye")
 }
+
+// listenMetrics binds the Prometheus /metrics handler to the node's
+// Tailscale IPv4 address, so the single scrape target is reachable over
+// the tailnet only.
+func listenMetrics(ts *tailscale.Server, port string) (net.Listener, error) {
+	if ts.TailscaleIP() == "" {
+		return nil, fmt.Errorf("no tailscale IPv4 address assigned")
+	}
+
+	return ts.Listen("tcp4", net.JoinHostPort(ts.TailscaleIP(), port))
+}
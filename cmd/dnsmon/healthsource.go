@@ -0,0 +1,95 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"net/netip"
+	"sync"
+
+	"github.com/hugginsio/skopos/internal/healthcheck"
+	"github.com/hugginsio/skopos/internal/rpc"
+)
+
+// healthSource adapts a *healthcheck.Checker to rpc.HealthSource, converting
+// between the checker's netip.Addr/healthcheck.State targets and the plain
+// strings the RPC wire format uses.
+type healthSource struct {
+	checker *healthcheck.Checker
+}
+
+func (h *healthSource) List() []string {
+	targets := h.checker.List()
+
+	out := make([]string, len(targets))
+	for i, target := range targets {
+		out[i] = target.String()
+	}
+
+	return out
+}
+
+func (h *healthSource) GetHealth(target string) (string, bool) {
+	addr, err := netip.ParseAddr(target)
+	if err != nil {
+		return "", false
+	}
+
+	state, ok := h.checker.State(addr)
+	if !ok {
+		return "", false
+	}
+
+	return state.String(), true
+}
+
+func (h *healthSource) ForceRecheck(target string) error {
+	addr, err := netip.ParseAddr(target)
+	if err != nil {
+		return err
+	}
+
+	return h.checker.ForceRecheck(addr)
+}
+
+// Subscribe forwards checker events onto an rpc.Event channel, translating
+// types as it goes. The forwarding goroutine selects against done (closed
+// by the returned stop function) on both its receive from events and its
+// send to out, so a caller that stops draining out (e.g. ServeWatch, once
+// its connection drops) causes the goroutine to exit instead of leaking it
+// blocked forever on a send nobody will ever read.
+func (h *healthSource) Subscribe() (<-chan rpc.Event, func()) {
+	events, unsubscribe := h.checker.Subscribe()
+
+	out := make(chan rpc.Event)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				select {
+				case out <- rpc.Event{Target: event.Target.String(), State: event.State.String()}:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(func() { close(done) })
+		unsubscribe()
+	}
+
+	return out, stop
+}
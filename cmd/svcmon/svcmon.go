@@ -4,11 +4,118 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	goversion "github.com/caarlos0/go-version"
+
+	"github.com/hugginsio/skopos/internal/config"
+	"github.com/hugginsio/skopos/internal/rpc"
+	"github.com/hugginsio/skopos/internal/tailscale"
 )
 
 func main() {
-	slog.Info("skopos svcmon starting", "version", goversion.GetVersionInfo().GitVersion)
+	cfg, err := config.Load("skopos-svcmon")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+		os.Exit(5)
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if cfg.Development {
+		handlerOptions.Level = slog.LevelDebug
+	}
+
+	handler := slog.NewTextHandler(os.Stdout, handlerOptions)
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+
+	logger.Info("skopos svcmon starting", "version", goversion.GetVersionInfo().GitVersion, "development", cfg.Development)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+
+	ts, err := tailscale.New(tailscale.Config{
+		Hostname:     cfg.Tailscale.Hostname,
+		StateDir:     cfg.Tailscale.StateDir,
+		AuthKey:      cfg.Tailscale.AuthKey,
+		Logger:       logger,
+		Ephemeral:    cfg.Tailscale.Ephemeral,
+		Routes:       cfg.Tailscale.Routes,
+		AcceptRoutes: cfg.Tailscale.AcceptRoutes,
+		Tags:         cfg.Tailscale.Tags,
+		Userspace:    cfg.Tailscale.Userspace,
+		ExtraArgs:    cfg.Tailscale.ExtraArgs,
+	})
+	if err != nil {
+		logger.Error("failed to create Tailscale server", "error", err)
+		os.Exit(5)
+	}
+
+	if err := ts.Start(ctx); err != nil {
+		logger.Error("failed to start Tailscale", "error", err)
+		os.Exit(5)
+	}
+
+	client, err := rpc.NewClient(ctx, ts, cfg.RPC.DNSMonHost)
+	if err != nil {
+		logger.Error("failed to connect to dnsmon", "host", cfg.RPC.DNSMonHost, "error", err)
+		os.Exit(5)
+	}
+
+	targets, err := client.ListTargets()
+	if err != nil {
+		logger.Error("failed to list targets", "error", err)
+		os.Exit(5)
+	}
+	logger.Info("connected to dnsmon", "host", cfg.RPC.DNSMonHost, "targets", targets)
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+
+	events, err := client.WatchHealth(watchCtx)
+	if err != nil {
+		logger.Error("failed to watch health", "error", err)
+		os.Exit(5)
+	}
+
+	logger.Info("svcmon OK")
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				logger.Warn("health watch stream closed")
+				events = nil
+				continue
+			}
+
+			// Downstream consumers (e.g. reloading a local HAProxy config)
+			// would react to the transition here.
+			logger.Info("health state transition", "target", event.Target, "state", event.State)
+
+		case sig := <-sigChan:
+			logger.Info("received shutdown signal", "signal", sig)
+
+			stopWatch()
+
+			if err := client.Close(); err != nil {
+				logger.Error("error closing RPC client", "error", err)
+			}
+
+			if err := ts.Close(); err != nil {
+				logger.Error("error closing Tailscale server", "error", err)
+			}
+
+			logger.Info("goodbye")
+			return
+		}
+	}
 }
@@ -5,27 +5,36 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/adrg/xdg"
 )
 
-// Config holds all configuration for the dnsmon service.
+// Config holds all configuration shared by the dnsmon and svcmon binaries.
 type Config struct {
 	Tailscale   TailscaleConfig   // Tailscale configuration
 	HealthCheck HealthCheckConfig // Health check configuration
 	DNS         DNSConfig         // DNS update configuration
+	RPC         RPCConfig         // RPC peer configuration
 	Development bool              // Development mode
 }
 
 // TailscaleConfig holds Tailscale-specific configuration.
 type TailscaleConfig struct {
-	AuthKey  string // AuthKey is the Tailscale authentication key.
-	Hostname string // Hostname is the hostname to use for this tsnet node.
-	StateDir string // StateDir is the directory where tsnet state is stored.
+	AuthKey      string         // AuthKey is the Tailscale authentication key.
+	Hostname     string         // Hostname is the hostname to use for this tsnet node.
+	StateDir     string         // StateDir is the directory where tsnet state is stored.
+	Routes       []netip.Prefix // Routes are subnet routes to advertise, from TS_ROUTES.
+	AcceptRoutes bool           // AcceptRoutes indicates whether to accept routes advertised by other nodes, from TS_ACCEPT_ROUTES.
+	Ephemeral    bool           // Ephemeral indicates whether this node should be ephemeral, from TS_EPHEMERAL.
+	Userspace    bool           // Userspace is reserved for userspace networking mode, from TS_USERSPACE; currently a no-op.
+	Tags         []string       // Tags are ACL tags to advertise, from TS_TAGS.
+	ExtraArgs    []string       // ExtraArgs is a whitelisted set of additional preference flags, from TS_EXTRA_ARGS.
 }
 
 // HealthCheckConfig holds health check configuration.
@@ -33,22 +42,40 @@ type HealthCheckConfig struct {
 	Interval           time.Duration // Interval is how often to check device health.
 	Timeout            time.Duration // Timeout is how long to wait for a health check to complete.
 	UnhealthyThreshold int           // UnhealthyThreshold is how many consecutive failures before marking unhealthy.
+	Targets            []TargetSpec  // Targets is the set of targets to health check, from HEALTH_CHECK_TARGETS and HEALTH_CHECK_FQDN_TARGETS.
+}
+
+// TargetSpec identifies a health check target, either a static Tailscale
+// IP or an FQDN/MagicDNS name that the checker re-resolves periodically.
+type TargetSpec struct {
+	IP              netip.Addr    // IP is a static target. Mutually exclusive with FQDN.
+	FQDN            string        // FQDN is re-resolved on ResolveInterval. Mutually exclusive with IP.
+	ResolveInterval time.Duration // ResolveInterval is how often to re-resolve FQDN. Ignored for IP targets.
 }
 
 // DNSConfig holds DNS update configuration.
 type DNSConfig struct {
 	UpdateTimeout time.Duration // UpdateTimeout is how long to wait for a DNS update to complete.
+	Upstreams     []string      // Upstreams is the list of upstream DNS servers (host:port) queries are forwarded to.
+}
+
+// RPCConfig holds configuration for the RPC link between dnsmon and
+// svcmon.
+type RPCConfig struct {
+	DNSMonHost string // DNSMonHost is the MagicDNS hostname svcmon dials to reach dnsmon, from DNSMON_HOST.
 }
 
-// Load loads configuration from environment variables with sensible defaults.
-func Load() (*Config, error) {
+// Load loads configuration from environment variables with sensible
+// defaults. defaultHostname is used for TS_HOSTNAME if unset, so each
+// binary can advertise a distinct default tsnet hostname.
+func Load(defaultHostname string) (*Config, error) {
 	cfg := &Config{
 		Development: os.Getenv("DEVEL") == "true",
 	}
 
 	// Load Tailscale configuration
 	cfg.Tailscale.AuthKey = os.Getenv("TS_AUTHKEY")
-	cfg.Tailscale.Hostname = getEnvOrDefault("TS_HOSTNAME", "skopos-dnsmon")
+	cfg.Tailscale.Hostname = getEnvOrDefault("TS_HOSTNAME", defaultHostname)
 
 	stateDir, err := determineStateDir(cfg.Development)
 	if err != nil {
@@ -56,14 +83,48 @@ func Load() (*Config, error) {
 	}
 
 	cfg.Tailscale.StateDir = getEnvOrDefault("TS_STATE_DIR", stateDir)
+	cfg.Tailscale.AcceptRoutes = getBoolOrDefault("TS_ACCEPT_ROUTES", false)
+	cfg.Tailscale.Ephemeral = getBoolOrDefault("TS_EPHEMERAL", false)
+	cfg.Tailscale.Userspace = getBoolOrDefault("TS_USERSPACE", false)
+	cfg.Tailscale.Tags = getStringSliceOrDefault("TS_TAGS", nil)
+	cfg.Tailscale.ExtraArgs = getStringSliceOrDefault("TS_EXTRA_ARGS", nil)
+
+	routes, err := parsePrefixList(os.Getenv("TS_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TS_ROUTES: %w", err)
+	}
+	cfg.Tailscale.Routes = routes
 
 	// Load health check configuration
 	cfg.HealthCheck.Interval = getDurationOrDefault("HEALTH_CHECK_INTERVAL", 20*time.Second)
 	cfg.HealthCheck.Timeout = getDurationOrDefault("HEALTH_CHECK_TIMEOUT", 5*time.Second)
 	cfg.HealthCheck.UnhealthyThreshold = getIntOrDefault("UNHEALTHY_THRESHOLD", 1)
 
+	ipTargets, err := parseAddrList(os.Getenv("HEALTH_CHECK_TARGETS"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HEALTH_CHECK_TARGETS: %w", err)
+	}
+
+	fqdnResolveInterval := getDurationOrDefault("HEALTH_CHECK_FQDN_RESOLVE_INTERVAL", 30*time.Second)
+
+	fqdnTargets, err := parseFQDNTargetList(os.Getenv("HEALTH_CHECK_FQDN_TARGETS"), fqdnResolveInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HEALTH_CHECK_FQDN_TARGETS: %w", err)
+	}
+
+	targets := make([]TargetSpec, 0, len(ipTargets)+len(fqdnTargets))
+	for _, ip := range ipTargets {
+		targets = append(targets, TargetSpec{IP: ip})
+	}
+	targets = append(targets, fqdnTargets...)
+	cfg.HealthCheck.Targets = targets
+
 	// Load DNS configuration
 	cfg.DNS.UpdateTimeout = getDurationOrDefault("DNS_UPDATE_TIMEOUT", 10*time.Second)
+	cfg.DNS.Upstreams = getStringSliceOrDefault("DNS_UPSTREAMS", []string{"1.1.1.1:53", "1.0.0.1:53"})
+
+	// Load RPC configuration
+	cfg.RPC.DNSMonHost = getEnvOrDefault("DNSMON_HOST", "skopos-dnsmon")
 
 	// Validate required fields
 	if cfg.Tailscale.AuthKey == "" {
@@ -133,3 +194,122 @@ func getIntOrDefault(key string, defaultValue int) int {
 
 	return defaultValue
 }
+
+// getBoolOrDefault parses a boolean from an environment variable or returns a default.
+func getBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+
+	return defaultValue
+}
+
+// getStringSliceOrDefault parses a comma-separated list from an environment
+// variable or returns a default.
+func getStringSliceOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+
+	if len(result) == 0 {
+		return defaultValue
+	}
+
+	return result
+}
+
+// parsePrefixList parses a comma-separated list of CIDR prefixes. An empty
+// string yields a nil slice.
+func parsePrefixList(value string) ([]netip.Prefix, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	prefixes := make([]netip.Prefix, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q: %w", trimmed, err)
+		}
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+// parseAddrList parses a comma-separated list of IP addresses. An empty
+// string yields a nil slice.
+func parseAddrList(value string) ([]netip.Addr, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	addrs := make([]netip.Addr, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+
+		addr, err := netip.ParseAddr(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", trimmed, err)
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
+
+// parseFQDNTargetList parses a comma-separated list of FQDN health check
+// targets. Each entry is an FQDN, optionally suffixed with "@<duration>"
+// to override defaultInterval for that target's re-resolution. An empty
+// string yields a nil slice.
+func parseFQDNTargetList(value string, defaultInterval time.Duration) ([]TargetSpec, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	specs := make([]TargetSpec, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+
+		fqdn, intervalStr, hasInterval := strings.Cut(trimmed, "@")
+		interval := defaultInterval
+		if hasInterval {
+			parsed, err := time.ParseDuration(intervalStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid resolve interval for %q: %w", fqdn, err)
+			}
+			interval = parsed
+		}
+
+		specs = append(specs, TargetSpec{FQDN: fqdn, ResolveInterval: interval})
+	}
+
+	return specs, nil
+}
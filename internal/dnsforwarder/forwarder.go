@@ -0,0 +1,293 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package dnsforwarder implements a minimal DNS forwarding proxy that binds
+// UDP and TCP port 53 on a tailscale.Server's tailnet addresses and relays
+// queries to a configurable set of upstream resolvers.
+package dnsforwarder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hugginsio/skopos/internal/tailscale"
+)
+
+const dnsPort = "53"
+
+// listener is the subset of tailscale.Server used by Forwarder. It exists so
+// binding can be exercised against a fake tsnet testnet in tests.
+type listener interface {
+	Listen(network, address string) (net.Listener, error)
+	ListenPacket(network, address string) (net.PacketConn, error)
+	TailscaleIP() string
+	TailscaleIPv6() string
+}
+
+// Recorder receives the outcome of each forwarded DNS update, for metrics
+// reporting.
+type Recorder interface {
+	RecordDNSUpdate(success bool)
+}
+
+// Config holds configuration for creating a new Forwarder.
+type Config struct {
+	Upstreams []string      // Upstreams is the list of upstream DNS servers (host:port) to forward queries to.
+	Timeout   time.Duration // Timeout bounds dialing an upstream and, for UDP, waiting for its response. TCP relays have no deadline once connected.
+	Logger    *slog.Logger  // Logger is the logger to use for forwarder operations.
+	Recorder  Recorder      // Recorder optionally receives per-query metrics. May be nil.
+}
+
+// Forwarder binds DNS listeners on a tailnet node and forwards queries to
+// upstream resolvers.
+type Forwarder struct {
+	server    listener
+	upstreams []string
+	timeout   time.Duration
+	logger    *slog.Logger
+	recorder  Recorder
+
+	next atomic.Uint64
+
+	udp4 net.PacketConn
+	udp6 net.PacketConn
+	tcp4 net.Listener
+	tcp6 net.Listener
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// New creates a new Forwarder with the given configuration.
+func New(server *tailscale.Server, cfg Config) (*Forwarder, error) {
+	return newForwarder(server, cfg)
+}
+
+func newForwarder(server listener, cfg Config) (*Forwarder, error) {
+	if len(cfg.Upstreams) == 0 {
+		return nil, fmt.Errorf("at least one upstream DNS server is required")
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	return &Forwarder{
+		server:    server,
+		upstreams: cfg.Upstreams,
+		timeout:   cfg.Timeout,
+		logger:    cfg.Logger,
+		recorder:  cfg.Recorder,
+	}, nil
+}
+
+// Start binds UDP/53 and TCP/53 on the node's Tailscale IPv4 and, if
+// available, IPv6 addresses and begins forwarding queries to the
+// configured upstreams.
+func (f *Forwarder) Start(ctx context.Context) error {
+	if f.server.TailscaleIP() == "" {
+		return fmt.Errorf("no tailscale IPv4 address assigned")
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	f.cancel = cancel
+
+	udp4, err := f.server.ListenPacket("udp4", net.JoinHostPort(f.server.TailscaleIP(), dnsPort))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to listen udp4: %w", err)
+	}
+	f.udp4 = udp4
+
+	tcp4, err := f.server.Listen("tcp4", net.JoinHostPort(f.server.TailscaleIP(), dnsPort))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to listen tcp4: %w", err)
+	}
+	f.tcp4 = tcp4
+
+	f.wg.Add(2)
+	go f.serveUDP(runCtx, f.udp4)
+	go f.serveTCP(f.tcp4)
+
+	if ipv6 := f.server.TailscaleIPv6(); ipv6 != "" {
+		udp6, err := f.server.ListenPacket("udp6", net.JoinHostPort(ipv6, dnsPort))
+		if err != nil {
+			return fmt.Errorf("failed to listen udp6: %w", err)
+		}
+		f.udp6 = udp6
+
+		tcp6, err := f.server.Listen("tcp6", net.JoinHostPort(ipv6, dnsPort))
+		if err != nil {
+			return fmt.Errorf("failed to listen tcp6: %w", err)
+		}
+		f.tcp6 = tcp6
+
+		f.wg.Add(2)
+		go f.serveUDP(runCtx, f.udp6)
+		go f.serveTCP(f.tcp6)
+	}
+
+	f.logger.Info("DNS forwarder listening", "ipv4", f.server.TailscaleIP(), "ipv6", f.server.TailscaleIPv6(), "upstreams", f.upstreams)
+
+	return nil
+}
+
+// Close stops the forwarder and closes all listeners.
+func (f *Forwarder) Close() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+
+	for _, c := range []io.Closer{f.udp4, f.udp6, f.tcp4, f.tcp6} {
+		if c != nil {
+			_ = c.Close()
+		}
+	}
+
+	f.wg.Wait()
+
+	return nil
+}
+
+// nextUpstream returns the next upstream server to use, round-robin.
+func (f *Forwarder) nextUpstream() string {
+	n := f.next.Add(1) - 1
+	return f.upstreams[n%uint64(len(f.upstreams))]
+}
+
+func (f *Forwarder) serveUDP(ctx context.Context, conn net.PacketConn) {
+	defer f.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			f.logger.Warn("DNS forwarder: udp read failed", "error", err)
+			continue
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+
+		go f.forwardUDP(conn, addr, query)
+	}
+}
+
+func (f *Forwarder) forwardUDP(conn net.PacketConn, addr net.Addr, query []byte) {
+	success := false
+	defer func() { f.recordUpdate(success) }()
+
+	upstream := f.nextUpstream()
+
+	uconn, err := net.DialTimeout("udp", upstream, f.timeout)
+	if err != nil {
+		f.logger.Warn("DNS forwarder: failed to dial upstream", "upstream", upstream, "error", err)
+		return
+	}
+	defer uconn.Close()
+
+	if _, err := uconn.Write(query); err != nil {
+		f.logger.Warn("DNS forwarder: failed to write query upstream", "upstream", upstream, "error", err)
+		return
+	}
+
+	if err := uconn.SetReadDeadline(time.Now().Add(f.timeout)); err != nil {
+		f.logger.Warn("DNS forwarder: failed to set read deadline", "error", err)
+		return
+	}
+
+	resp := make([]byte, 65535)
+	n, err := uconn.Read(resp)
+	if err != nil {
+		f.logger.Warn("DNS forwarder: failed to read response from upstream", "upstream", upstream, "error", err)
+		return
+	}
+
+	if _, err := conn.WriteTo(resp[:n], addr); err != nil {
+		f.logger.Warn("DNS forwarder: failed to write response to client", "error", err)
+		return
+	}
+
+	success = true
+}
+
+// recordUpdate reports the outcome of a forwarded query to the configured
+// Recorder, if any.
+func (f *Forwarder) recordUpdate(success bool) {
+	if f.recorder != nil {
+		f.recorder.RecordDNSUpdate(success)
+	}
+}
+
+func (f *Forwarder) serveTCP(ln net.Listener) {
+	defer f.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go f.forwardTCP(conn)
+	}
+}
+
+func (f *Forwarder) forwardTCP(conn net.Conn) {
+	defer conn.Close()
+
+	upstream := f.nextUpstream()
+
+	uconn, err := net.DialTimeout("tcp", upstream, f.timeout)
+	if err != nil {
+		f.logger.Warn("DNS forwarder: failed to dial upstream", "upstream", upstream, "error", err)
+		f.recordUpdate(false)
+		return
+	}
+	defer uconn.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	var sendErr, recvErr error
+
+	go func() {
+		defer wg.Done()
+		_, sendErr = io.Copy(uconn, conn)
+		if tc, ok := uconn.(*net.TCPConn); ok {
+			_ = tc.CloseWrite()
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, recvErr = io.Copy(conn, uconn)
+		if tc, ok := conn.(*net.TCPConn); ok {
+			_ = tc.CloseWrite()
+		}
+	}()
+
+	wg.Wait()
+
+	if sendErr != nil {
+		f.logger.Warn("DNS forwarder: failed to relay query to upstream", "upstream", upstream, "error", sendErr)
+	}
+	if recvErr != nil {
+		f.logger.Warn("DNS forwarder: failed to relay response from upstream", "upstream", upstream, "error", recvErr)
+	}
+
+	f.recordUpdate(sendErr == nil && recvErr == nil)
+}
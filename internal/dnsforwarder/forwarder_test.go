@@ -0,0 +1,215 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package dnsforwarder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeListener implements the listener interface against real loopback
+// sockets. It stands in for a tsnet.Server testnet: binding port 53 over
+// loopback exercises the exact same Forwarder code path (Listen,
+// ListenPacket, the UDP/TCP relay goroutines) without requiring a live
+// tailnet or network access to fetch tailscale.com's test harness.
+type fakeListener struct {
+	ip string
+}
+
+func (f *fakeListener) Listen(network, address string) (net.Listener, error) {
+	return net.Listen(network, address)
+}
+
+func (f *fakeListener) ListenPacket(network, address string) (net.PacketConn, error) {
+	return net.ListenPacket(network, address)
+}
+
+func (f *fakeListener) TailscaleIP() string {
+	return f.ip
+}
+
+func (f *fakeListener) TailscaleIPv6() string {
+	return ""
+}
+
+// echoUpstream answers every query on conn with the query payload reversed,
+// so the test can tell a genuine round trip apart from a trivial echo.
+func echoUpstream(t *testing.T, conn net.PacketConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		resp := make([]byte, n)
+		for i, b := range buf[:n] {
+			resp[n-1-i] = b
+		}
+
+		if _, err := conn.WriteTo(resp, addr); err != nil {
+			t.Logf("echoUpstream: write failed: %v", err)
+		}
+	}
+}
+
+// tcpEchoUpstream answers every connection accepted on ln with the request
+// payload reversed, once the client half-closes its write side.
+func tcpEchoUpstream(t *testing.T, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			data, err := io.ReadAll(conn)
+			if err != nil {
+				t.Logf("tcpEchoUpstream: read failed: %v", err)
+				return
+			}
+
+			if _, err := conn.Write(reverse(data)); err != nil {
+				t.Logf("tcpEchoUpstream: write failed: %v", err)
+			}
+		}()
+	}
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[len(b)-1-i] = c
+	}
+	return out
+}
+
+// TestForwarderUDPRoundTrip binds a Forwarder on loopback port 53 and
+// verifies a UDP query reaches the configured upstream and its response is
+// relayed back to the client unmodified.
+func TestForwarderUDPRoundTrip(t *testing.T) {
+	upstream, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake upstream: %v", err)
+	}
+	defer upstream.Close()
+
+	go echoUpstream(t, upstream)
+
+	fwd, err := newForwarder(&fakeListener{ip: "127.0.0.1"}, Config{
+		Upstreams: []string{upstream.LocalAddr().String()},
+		Timeout:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("newForwarder: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := fwd.Start(ctx); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			t.Skipf("skipping, cannot bind privileged port 53 in this environment: %v", err)
+		}
+		t.Fatalf("fwd.Start: %v", err)
+	}
+	defer fwd.Close()
+
+	conn, err := net.Dial("udp4", net.JoinHostPort("127.0.0.1", dnsPort))
+	if err != nil {
+		t.Fatalf("failed to dial forwarder: %v", err)
+	}
+	defer conn.Close()
+
+	query := []byte("skopos-dnsforwarder-test-query")
+	if _, err := conn.Write(query); err != nil {
+		t.Fatalf("failed to write query: %v", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+
+	resp := make([]byte, 512)
+	n, err := conn.Read(resp)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	if !bytes.Equal(resp[:n], reverse(query)) {
+		t.Fatalf("response = %q, want %q", resp[:n], reverse(query))
+	}
+}
+
+// TestForwarderTCPRoundTrip binds a Forwarder on loopback port 53 and
+// verifies a TCP query reaches the configured upstream and its response is
+// relayed back to the client unmodified.
+func TestForwarderTCPRoundTrip(t *testing.T) {
+	upstreamLn, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake upstream: %v", err)
+	}
+	defer upstreamLn.Close()
+
+	go tcpEchoUpstream(t, upstreamLn)
+
+	fwd, err := newForwarder(&fakeListener{ip: "127.0.0.1"}, Config{
+		Upstreams: []string{upstreamLn.Addr().String()},
+		Timeout:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("newForwarder: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := fwd.Start(ctx); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			t.Skipf("skipping, cannot bind privileged port 53 in this environment: %v", err)
+		}
+		t.Fatalf("fwd.Start: %v", err)
+	}
+	defer fwd.Close()
+
+	conn, err := net.Dial("tcp4", net.JoinHostPort("127.0.0.1", dnsPort))
+	if err != nil {
+		t.Fatalf("failed to dial forwarder: %v", err)
+	}
+	defer conn.Close()
+
+	query := []byte("skopos-dnsforwarder-tcp-test-query")
+	if _, err := conn.Write(query); err != nil {
+		t.Fatalf("failed to write query: %v", err)
+	}
+
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		t.Fatalf("conn is not a *net.TCPConn")
+	}
+	if err := tc.CloseWrite(); err != nil {
+		t.Fatalf("failed to half-close connection: %v", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+
+	resp, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	if !bytes.Equal(resp, reverse(query)) {
+		t.Fatalf("response = %q, want %q", resp, reverse(query))
+	}
+}
@@ -0,0 +1,491 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package healthcheck performs in-process ICMP echo health checks against
+// tailnet devices, using the node's userspace netstack rather than
+// exec'ing ping(1) (which would not route over the tailnet and does not
+// work in a distroless container anyway).
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"sync"
+	"time"
+
+	"tailscale.com/net/ping"
+
+	"github.com/hugginsio/skopos/internal/tailscale"
+)
+
+// defaultDebounce is how long a re-resolved FQDN target's IP set must
+// stay stable before the checker acts on it, so transient tailnet churn
+// doesn't thrash probers.
+const defaultDebounce = 1 * time.Minute
+
+// State represents the health of a target.
+type State int
+
+const (
+	StateUnknown State = iota
+	StateHealthy
+	StateUnhealthy
+)
+
+// String returns a human-readable name for the state.
+func (s State) String() string {
+	switch s {
+	case StateHealthy:
+		return "healthy"
+	case StateUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// TargetSpec identifies a health check target, either by a static
+// Tailscale IP or by an FQDN/MagicDNS name that is periodically
+// re-resolved to the IPs it currently points at.
+type TargetSpec struct {
+	IP              netip.Addr    // IP is a static target. Mutually exclusive with FQDN.
+	FQDN            string        // FQDN is re-resolved on ResolveInterval. Mutually exclusive with IP.
+	ResolveInterval time.Duration // ResolveInterval is how often to re-resolve FQDN. Zero uses Config.Interval.
+}
+
+func (t TargetSpec) isFQDN() bool {
+	return t.FQDN != ""
+}
+
+// Event describes a health state transition for a target.
+type Event struct {
+	Target netip.Addr
+	State  State
+}
+
+// Recorder receives the outcome of each probe, for metrics reporting.
+type Recorder interface {
+	RecordHealthCheck(target netip.Addr, success bool, latency time.Duration)
+}
+
+// Config holds configuration for creating a new Checker.
+type Config struct {
+	Targets            []TargetSpec  // Targets is the set of targets to health check.
+	Interval           time.Duration // Interval is how often to probe each target.
+	Timeout            time.Duration // Timeout is how long to wait for an echo reply.
+	UnhealthyThreshold int           // UnhealthyThreshold is the number of consecutive failures before a target is marked unhealthy.
+	Debounce           time.Duration // Debounce is how long a re-resolved FQDN's IP set must be stable before it is acted on. Defaults to defaultDebounce.
+	Logger             *slog.Logger  // Logger is the logger to use for checker operations.
+	Recorder           Recorder      // Recorder optionally receives per-probe metrics. May be nil.
+}
+
+// Checker periodically sends ICMP echo requests to a set of tailnet
+// targets and tracks their health state. Targets configured by FQDN are
+// re-resolved on an interval; the set of IPs actually probed grows and
+// shrinks as that resolution changes.
+type Checker struct {
+	pinger             *ping.Pinger
+	resolver           resolver
+	specs              []TargetSpec
+	interval           time.Duration
+	timeout            time.Duration
+	unhealthyThreshold int
+	debounce           time.Duration
+	logger             *slog.Logger
+	recorder           Recorder
+
+	mu      sync.Mutex
+	states  map[netip.Addr]*targetState
+	runners map[netip.Addr]context.CancelFunc
+
+	subMu     sync.Mutex
+	subs      map[int]chan Event
+	nextSubID int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type targetState struct {
+	state               State
+	consecutiveFailures int
+}
+
+// New creates a new Checker backed by the given tailscale.Server's
+// netstack. FQDN targets are resolved via the same server's LocalClient.
+func New(server *tailscale.Server, cfg Config) (*Checker, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("at least one health check target is required")
+	}
+
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = 1
+	}
+
+	if cfg.Debounce <= 0 {
+		cfg.Debounce = defaultDebounce
+	}
+
+	ns, err := server.Netstack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get netstack: %w", err)
+	}
+
+	pinger := ping.New(context.Background(), func(format string, args ...any) {
+		cfg.Logger.Debug(fmt.Sprintf(format, args...))
+	}, ns)
+
+	states := make(map[netip.Addr]*targetState)
+	for _, spec := range cfg.Targets {
+		if !spec.isFQDN() {
+			states[spec.IP] = &targetState{state: StateUnknown}
+		}
+	}
+
+	return &Checker{
+		pinger:             pinger,
+		resolver:           newTailscaleResolver(server),
+		specs:              cfg.Targets,
+		interval:           cfg.Interval,
+		timeout:            cfg.Timeout,
+		unhealthyThreshold: cfg.UnhealthyThreshold,
+		debounce:           cfg.Debounce,
+		logger:             cfg.Logger,
+		recorder:           cfg.Recorder,
+		states:             states,
+		runners:            make(map[netip.Addr]context.CancelFunc),
+		subs:               make(map[int]chan Event),
+	}, nil
+}
+
+// List returns the set of targets this checker is currently probing. For
+// FQDN targets this is the most recently applied (debounced) IP set, not
+// the FQDN itself.
+func (c *Checker) List() []netip.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	targets := make([]netip.Addr, 0, len(c.states))
+	for target := range c.states {
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// State returns the current health state of target, and whether target is
+// known to this checker.
+func (c *Checker) State(target netip.Addr) (State, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, ok := c.states[target]
+	if !ok {
+		return StateUnknown, false
+	}
+
+	return st.state, true
+}
+
+// ForceRecheck immediately probes target, outside of its regular interval.
+func (c *Checker) ForceRecheck(target netip.Addr) error {
+	c.mu.Lock()
+	_, ok := c.states[target]
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown target %s", target)
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.probe(context.Background(), target)
+	}()
+
+	return nil
+}
+
+// Subscribe registers a new listener for health state transitions and
+// returns a channel of events alongside an unsubscribe function. Callers
+// must call unsubscribe when done to avoid leaking the channel.
+func (c *Checker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 8)
+
+	c.subMu.Lock()
+	id := c.nextSubID
+	c.nextSubID++
+	c.subs[id] = ch
+	c.subMu.Unlock()
+
+	unsubscribe := func() {
+		c.subMu.Lock()
+		defer c.subMu.Unlock()
+
+		if ch, ok := c.subs[id]; ok {
+			delete(c.subs, id)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (c *Checker) publish(event Event) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for _, ch := range c.subs {
+		select {
+		case ch <- event:
+		default:
+			c.logger.Warn("health check subscriber channel full, dropping event", "target", event.Target)
+		}
+	}
+}
+
+// Start begins periodically health checking all configured targets,
+// resolving and re-resolving FQDN targets as it goes.
+func (c *Checker) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+
+	for _, spec := range c.specs {
+		if spec.isFQDN() {
+			c.wg.Add(1)
+			go c.watchFQDN(runCtx, spec)
+		} else {
+			c.startTarget(runCtx, spec.IP)
+		}
+	}
+
+	return nil
+}
+
+// Close stops the checker and releases the underlying pinger.
+func (c *Checker) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.wg.Wait()
+
+	c.subMu.Lock()
+	for id, ch := range c.subs {
+		delete(c.subs, id)
+		close(ch)
+	}
+	c.subMu.Unlock()
+
+	return c.pinger.Close()
+}
+
+// startTarget registers target and begins probing it in its own
+// goroutine, derived from ctx so it is stopped when ctx is canceled or
+// stopTarget is called.
+func (c *Checker) startTarget(ctx context.Context, target netip.Addr) {
+	targetCtx, cancel := context.WithCancel(ctx)
+
+	c.mu.Lock()
+	if _, ok := c.states[target]; !ok {
+		c.states[target] = &targetState{state: StateUnknown}
+	}
+	c.runners[target] = cancel
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go c.run(targetCtx, target)
+}
+
+// stopTarget stops probing target and forgets its state.
+func (c *Checker) stopTarget(target netip.Addr) {
+	c.mu.Lock()
+	cancel, ok := c.runners[target]
+	if ok {
+		delete(c.runners, target)
+		delete(c.states, target)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// watchFQDN periodically re-resolves spec.FQDN and starts/stops probers
+// as the resolved IP set changes. The initial resolution on startup is
+// applied immediately, since there's no prior state it could be thrashing;
+// every subsequent change is debounced, requiring the newly resolved set
+// to be seen unchanged for at least c.debounce before it is acted on, so a
+// flapping MagicDNS record doesn't thrash probers.
+func (c *Checker) watchFQDN(ctx context.Context, spec TargetSpec) {
+	defer c.wg.Done()
+
+	interval := spec.ResolveInterval
+	if interval <= 0 {
+		interval = c.interval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	current := map[netip.Addr]bool{}
+	var pending map[netip.Addr]bool
+	var pendingSince time.Time
+	coldStart := true
+
+	resolve := func() {
+		ips, err := c.resolver.Resolve(ctx, spec.FQDN)
+		if err != nil {
+			c.logger.Warn("failed to resolve FQDN target", "fqdn", spec.FQDN, "error", err)
+			return
+		}
+
+		resolved := toSet(ips)
+
+		if setEqual(resolved, current) {
+			pending = nil
+			return
+		}
+
+		if coldStart {
+			c.applyFQDNChange(ctx, spec.FQDN, current, resolved)
+			current = resolved
+			pending = nil
+			coldStart = false
+			return
+		}
+
+		if pending == nil || !setEqual(pending, resolved) {
+			pending = resolved
+			pendingSince = time.Now()
+			return
+		}
+
+		if time.Since(pendingSince) < c.debounce {
+			return
+		}
+
+		c.applyFQDNChange(ctx, spec.FQDN, current, resolved)
+		current = resolved
+		pending = nil
+	}
+
+	resolve()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for target := range current {
+				c.stopTarget(target)
+			}
+			return
+		case <-ticker.C:
+			resolve()
+		}
+	}
+}
+
+// applyFQDNChange starts probers for IPs newly present in next and stops
+// probers for IPs no longer present, logging each transition.
+func (c *Checker) applyFQDNChange(ctx context.Context, fqdn string, prev, next map[netip.Addr]bool) {
+	for target := range prev {
+		if !next[target] {
+			c.logger.Info("FQDN target resolved away from IP", "fqdn", fqdn, "target", target)
+			c.stopTarget(target)
+		}
+	}
+
+	for target := range next {
+		if !prev[target] {
+			c.logger.Info("FQDN target resolved to new IP", "fqdn", fqdn, "target", target)
+			c.startTarget(ctx, target)
+		}
+	}
+}
+
+func toSet(addrs []netip.Addr) map[netip.Addr]bool {
+	set := make(map[netip.Addr]bool, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = true
+	}
+
+	return set
+}
+
+func setEqual(a, b map[netip.Addr]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for target := range a {
+		if !b[target] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Checker) run(ctx context.Context, target netip.Addr) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.probe(ctx, target)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Checker) probe(ctx context.Context, target netip.Addr) {
+	probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.pinger.Send(probeCtx, target, []byte("skopos-healthcheck"))
+	latency := time.Since(start)
+
+	if c.recorder != nil {
+		c.recorder.RecordHealthCheck(target, err == nil, latency)
+	}
+
+	c.mu.Lock()
+	st, ok := c.states[target]
+	if !ok {
+		// target was removed (e.g. an FQDN resolved away from it)
+		// concurrently with this probe landing.
+		c.mu.Unlock()
+		return
+	}
+	prev := st.state
+
+	if err != nil {
+		st.consecutiveFailures++
+		if st.consecutiveFailures >= c.unhealthyThreshold {
+			st.state = StateUnhealthy
+		}
+	} else {
+		st.consecutiveFailures = 0
+		st.state = StateHealthy
+	}
+	next := st.state
+	c.mu.Unlock()
+
+	if next != prev {
+		c.logger.Info("health check state transition", "target", target, "state", next)
+		c.publish(Event{Target: target, State: next})
+	}
+}
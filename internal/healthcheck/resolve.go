@@ -0,0 +1,80 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"tailscale.com/ipn/ipnstate"
+
+	"github.com/hugginsio/skopos/internal/tailscale"
+)
+
+// resolver resolves an FQDN health check target to the Tailscale IPs it
+// currently points at.
+type resolver interface {
+	Resolve(ctx context.Context, fqdn string) ([]netip.Addr, error)
+}
+
+// tailscaleResolver resolves FQDN targets by first checking the tailnet's
+// own name resolution (via LocalClient.StatusWithPeers, so MagicDNS names
+// resolve correctly regardless of the host's system resolver
+// configuration), and falling back to the system resolver for names that
+// aren't a known tailnet peer.
+type tailscaleResolver struct {
+	server *tailscale.Server
+}
+
+func newTailscaleResolver(server *tailscale.Server) *tailscaleResolver {
+	return &tailscaleResolver{server: server}
+}
+
+func (r *tailscaleResolver) Resolve(ctx context.Context, fqdn string) ([]netip.Addr, error) {
+	name := strings.TrimSuffix(strings.ToLower(fqdn), ".")
+
+	if status, err := r.server.LocalClient().StatusWithPeers(ctx); err == nil {
+		if ips, ok := peerIPs(status, name); ok {
+			return ips, nil
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", fqdn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %q: %w", fqdn, err)
+	}
+
+	return addrs, nil
+}
+
+// peerIPs looks for a self or peer node in status matching name by
+// MagicDNS name or hostname.
+func peerIPs(status *ipnstate.Status, name string) ([]netip.Addr, bool) {
+	if status.Self != nil && matchesPeerName(status.Self, name) {
+		return status.Self.TailscaleIPs, true
+	}
+
+	for _, peer := range status.Peer {
+		if matchesPeerName(peer, name) {
+			return peer.TailscaleIPs, true
+		}
+	}
+
+	return nil, false
+}
+
+func matchesPeerName(peer *ipnstate.PeerStatus, name string) bool {
+	if peer == nil {
+		return false
+	}
+
+	if strings.EqualFold(strings.TrimSuffix(peer.DNSName, "."), name) {
+		return true
+	}
+
+	return strings.EqualFold(peer.HostName, name)
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package metrics exposes skopos's internal health state, alongside
+// tsnet's own, as a single Prometheus scrape target.
+package metrics
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"tailscale.com/health"
+)
+
+// Registry collects skopos-level metrics (health checks, DNS updates)
+// alongside tsnet's own health.Tracker warnings, and serves them all on a
+// single Prometheus handler.
+type Registry struct {
+	reg *prometheus.Registry
+
+	healthCheckTotal   *prometheus.CounterVec
+	healthCheckLatency *prometheus.HistogramVec
+	dnsUpdateTotal     *prometheus.CounterVec
+}
+
+// New creates a Registry that reports on tracker alongside skopos-level
+// metrics.
+func New(tracker *health.Tracker) *Registry {
+	reg := prometheus.NewRegistry()
+
+	r := &Registry{
+		reg: reg,
+		healthCheckTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "skopos",
+			Subsystem: "healthcheck",
+			Name:      "probes_total",
+			Help:      "Total number of ICMP echo health check probes, labeled by target and result.",
+		}, []string{"target", "result"}),
+		healthCheckLatency: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "skopos",
+			Subsystem: "healthcheck",
+			Name:      "probe_latency_seconds",
+			Help:      "Latency of successful ICMP echo health check probes.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"target"}),
+		dnsUpdateTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "skopos",
+			Subsystem: "dns",
+			Name:      "updates_total",
+			Help:      "Total number of DNS updates, labeled by result.",
+		}, []string{"result"}),
+	}
+
+	reg.MustRegister(newWarningsCollector(tracker))
+
+	return r
+}
+
+// RecordHealthCheck records the outcome of an ICMP echo probe against
+// target. It satisfies healthcheck.Recorder.
+func (r *Registry) RecordHealthCheck(target netip.Addr, success bool, latency time.Duration) {
+	result := "failure"
+	if success {
+		result = "success"
+		r.healthCheckLatency.WithLabelValues(target.String()).Observe(latency.Seconds())
+	}
+
+	r.healthCheckTotal.WithLabelValues(target.String(), result).Inc()
+}
+
+// RecordDNSUpdate records the outcome of a DNS update. It satisfies
+// dnsforwarder.Recorder.
+func (r *Registry) RecordDNSUpdate(success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+
+	r.dnsUpdateTotal.WithLabelValues(result).Inc()
+}
+
+// Handler returns the HTTP handler that serves this registry's metrics in
+// the Prometheus exposition format.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{})
+}
+
+// warningsCollector reports each active tsnet health.Tracker warning as a
+// gauge set to 1, so a single scrape surfaces both tsnet-internal and
+// skopos-level health. The "subsystem" label holds the warning's full text
+// as returned by Tracker.Strings; warnings are not grouped by subsystem.
+type warningsCollector struct {
+	tracker *health.Tracker
+	desc    *prometheus.Desc
+}
+
+func newWarningsCollector(tracker *health.Tracker) *warningsCollector {
+	return &warningsCollector{
+		tracker: tracker,
+		desc: prometheus.NewDesc(
+			"skopos_tailscale_warning",
+			"Active Tailscale health warning reported by tsnet's health.Tracker (1 while active).",
+			[]string{"subsystem"},
+			nil,
+		),
+	}
+}
+
+func (c *warningsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *warningsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, warning := range c.tracker.Strings() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1, warning)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package rpc
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"net/rpc"
+)
+
+// Dialer dials an address on the tailnet. It is satisfied by
+// tailscale.Server.
+type Dialer interface {
+	Dial(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Client is a client for dnsmon's RPC service, dialed over the tailnet by
+// MagicDNS hostname rather than by Tailscale IP so it keeps working across
+// node restarts.
+type Client struct {
+	dialer    Dialer
+	rpcAddr   string
+	watchAddr string
+
+	rpcClient *rpc.Client
+}
+
+// NewClient dials dnsmon's request/reply RPC service at host using
+// dialer. host is typically a MagicDNS hostname; the RPC and watch ports
+// are fixed at DefaultRPCPort and DefaultWatchPort.
+func NewClient(ctx context.Context, dialer Dialer, host string) (*Client, error) {
+	rpcAddr := net.JoinHostPort(host, DefaultRPCPort)
+
+	conn, err := dialer.Dial(ctx, "tcp", rpcAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", rpcAddr, err)
+	}
+
+	return &Client{
+		dialer:    dialer,
+		rpcAddr:   rpcAddr,
+		watchAddr: net.JoinHostPort(host, DefaultWatchPort),
+		rpcClient: rpc.NewClient(conn),
+	}, nil
+}
+
+// ListTargets returns the set of targets dnsmon is health checking.
+func (c *Client) ListTargets() ([]string, error) {
+	var reply ListTargetsReply
+	if err := c.rpcClient.Call(ServiceName+".ListTargets", &ListTargetsArgs{}, &reply); err != nil {
+		return nil, err
+	}
+
+	return reply.Targets, nil
+}
+
+// GetHealth returns the current health state of target.
+func (c *Client) GetHealth(target string) (string, error) {
+	var reply GetHealthReply
+	if err := c.rpcClient.Call(ServiceName+".GetHealth", &GetHealthArgs{Target: target}, &reply); err != nil {
+		return "", err
+	}
+
+	return reply.State, nil
+}
+
+// ForceRecheck asks dnsmon to immediately probe target, outside of its
+// regular interval. It requires this node to be a tagged tailnet node.
+func (c *Client) ForceRecheck(target string) error {
+	return c.rpcClient.Call(ServiceName+".ForceRecheck", &ForceRecheckArgs{Target: target}, &ForceRecheckReply{})
+}
+
+// WatchHealth dials dnsmon's watch stream and returns a channel of health
+// state transitions. The channel is closed when ctx is canceled or the
+// connection drops.
+func (c *Client) WatchHealth(ctx context.Context) (<-chan Event, error) {
+	conn, err := c.dialer.Dial(ctx, "tcp", c.watchAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", c.watchAddr, err)
+	}
+
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+		defer conn.Close()
+
+		dec := gob.NewDecoder(conn)
+
+		for {
+			var event Event
+			if err := dec.Decode(&event); err != nil {
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	return events, nil
+}
+
+// Close closes the client's RPC connection.
+func (c *Client) Close() error {
+	return c.rpcClient.Close()
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package rpc defines the RPC surface dnsmon exposes to other skopos
+// binaries (namely svcmon) over the tailnet, so both sides share one
+// definition of the service rather than hand-rolling their own wire
+// format.
+//
+// The service is a plain net/rpc server, not gRPC: skopos has no protoc
+// toolchain, and the tailnet link is already authenticated and encrypted
+// by WireGuard, so there is no TLS layer to add on top. Mutating calls are
+// additionally gated on the caller being a tagged tailnet node, checked
+// via LocalClient.WhoIs.
+package rpc
+
+const (
+	// DefaultRPCPort is the default port the request/reply RPC service
+	// (ListTargets, GetHealth, ForceRecheck) listens on.
+	DefaultRPCPort = "8081"
+
+	// DefaultWatchPort is the default port the WatchHealth event stream
+	// listens on.
+	DefaultWatchPort = "8082"
+
+	// ServiceName is the net/rpc service name dnsmon registers its RPC
+	// methods under.
+	ServiceName = "DNSMon"
+)
+
+// ListTargetsArgs holds arguments for DNSMon.ListTargets. It has no fields;
+// net/rpc requires a concrete argument type regardless.
+type ListTargetsArgs struct{}
+
+// ListTargetsReply holds the reply for DNSMon.ListTargets.
+type ListTargetsReply struct {
+	Targets []string
+}
+
+// GetHealthArgs holds arguments for DNSMon.GetHealth.
+type GetHealthArgs struct {
+	Target string
+}
+
+// GetHealthReply holds the reply for DNSMon.GetHealth.
+type GetHealthReply struct {
+	State string
+}
+
+// ForceRecheckArgs holds arguments for DNSMon.ForceRecheck.
+type ForceRecheckArgs struct {
+	Target string
+}
+
+// ForceRecheckReply holds the reply for DNSMon.ForceRecheck. It has no
+// fields; a non-nil error is how failure is reported.
+type ForceRecheckReply struct{}
+
+// Event describes a health state transition pushed over the WatchHealth
+// stream.
+type Event struct {
+	Target string
+	State  string
+}
@@ -0,0 +1,149 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package rpc
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/rpc"
+
+	"tailscale.com/client/local"
+)
+
+// HealthSource is the RPC server's view of a health checker. Targets and
+// states are plain strings rather than healthcheck.State/netip.Addr so
+// this package has no dependency on internal/healthcheck; callers adapt a
+// *healthcheck.Checker to this interface.
+type HealthSource interface {
+	List() []string
+	GetHealth(target string) (string, bool)
+	ForceRecheck(target string) error
+	Subscribe() (<-chan Event, func())
+}
+
+// Server exposes a HealthSource as a net/rpc service (ListTargets,
+// GetHealth, ForceRecheck) plus a WatchHealth event stream, both served on
+// listeners bound to a tsnet node's tailnet addresses.
+type Server struct {
+	source HealthSource
+	local  *local.Client
+	logger *slog.Logger
+}
+
+// NewServer creates a Server backed by source. local is used to authorize
+// mutating RPCs via WhoIs; it is typically tailscale.Server.LocalClient().
+func NewServer(source HealthSource, local *local.Client, logger *slog.Logger) *Server {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Server{source: source, local: local, logger: logger}
+}
+
+// ServeRPC accepts connections on ln and serves the request/reply RPC
+// service (ListTargets, GetHealth, ForceRecheck) on each. It blocks until
+// ln is closed.
+func (s *Server) ServeRPC(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serveRPCConn(conn)
+	}
+}
+
+func (s *Server) serveRPCConn(conn net.Conn) {
+	defer conn.Close()
+
+	srv := rpc.NewServer()
+	svc := &service{server: s, remoteAddr: conn.RemoteAddr().String()}
+
+	if err := srv.RegisterName(ServiceName, svc); err != nil {
+		s.logger.Error("failed to register RPC service", "error", err)
+		return
+	}
+
+	srv.ServeConn(conn)
+}
+
+// ServeWatch accepts connections on ln and, for each, streams gob-encoded
+// Events as they occur until the connection or subscription is closed. It
+// blocks until ln is closed.
+func (s *Server) ServeWatch(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serveWatchConn(conn)
+	}
+}
+
+func (s *Server) serveWatchConn(conn net.Conn) {
+	defer conn.Close()
+
+	events, unsubscribe := s.source.Subscribe()
+	defer unsubscribe()
+
+	enc := gob.NewEncoder(conn)
+
+	for event := range events {
+		if err := enc.Encode(event); err != nil {
+			s.logger.Debug("watch connection closed", "remote", conn.RemoteAddr(), "error", err)
+			return
+		}
+	}
+}
+
+// authorizeMutation checks that remoteAddr belongs to a tagged tailnet
+// node, so that only trusted peers can call mutating RPCs.
+func (s *Server) authorizeMutation(remoteAddr string) error {
+	who, err := s.local.WhoIs(context.Background(), remoteAddr)
+	if err != nil {
+		return fmt.Errorf("failed to identify caller: %w", err)
+	}
+
+	if who.Node == nil || len(who.Node.Tags) == 0 {
+		return fmt.Errorf("caller %s is not a tagged tailnet node", remoteAddr)
+	}
+
+	return nil
+}
+
+// service is the net/rpc-visible handler for a single connection. A fresh
+// instance is registered per connection so each has its own remoteAddr for
+// WhoIs-based authorization.
+type service struct {
+	server     *Server
+	remoteAddr string
+}
+
+func (s *service) ListTargets(args *ListTargetsArgs, reply *ListTargetsReply) error {
+	reply.Targets = s.server.source.List()
+	return nil
+}
+
+func (s *service) GetHealth(args *GetHealthArgs, reply *GetHealthReply) error {
+	state, ok := s.server.source.GetHealth(args.Target)
+	if !ok {
+		return fmt.Errorf("unknown target %q", args.Target)
+	}
+
+	reply.State = state
+	return nil
+}
+
+func (s *service) ForceRecheck(args *ForceRecheckArgs, reply *ForceRecheckReply) error {
+	if err := s.server.authorizeMutation(s.remoteAddr); err != nil {
+		return err
+	}
+
+	return s.server.source.ForceRecheck(args.Target)
+}
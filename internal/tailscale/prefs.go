@@ -0,0 +1,87 @@
+// Copyright (c) Kyle Huggins and contributors
+// SPDX-License-Identifier: BSD-3-Clause
+
+package tailscale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"tailscale.com/ipn"
+	"tailscale.com/net/tsaddr"
+)
+
+// extraArgWhitelist maps a TS_EXTRA_ARGS flag to a function that applies it
+// to an ipn.Prefs/ipn.MaskedPrefs pair. Only flags present in this map are
+// accepted; this mirrors the small, explicit set of extra flags containerboot
+// supports rather than accepting arbitrary `tailscale up` arguments.
+var extraArgWhitelist = map[string]func(value string, prefs *ipn.Prefs, mp *ipn.MaskedPrefs) error{
+	"--advertise-exit-node": func(value string, prefs *ipn.Prefs, mp *ipn.MaskedPrefs) error {
+		prefs.AdvertiseRoutes = append(prefs.AdvertiseRoutes, tsaddr.AllIPv4(), tsaddr.AllIPv6())
+		mp.AdvertiseRoutesSet = true
+		return nil
+	},
+	"--hostname": func(value string, prefs *ipn.Prefs, mp *ipn.MaskedPrefs) error {
+		if value == "" {
+			return fmt.Errorf("--hostname requires a value")
+		}
+		prefs.Hostname = value
+		mp.HostnameSet = true
+		return nil
+	},
+}
+
+// applyPrefs edits this node's preferences to match the Routes,
+// AcceptRoutes, Tags, and ExtraArgs supplied at construction time. It is
+// applied via localClient.EditPrefs rather than by re-authenticating, so
+// the node can change routes across restarts without losing state. The
+// mask bits for routes/accept-routes/tags are always set, even when the
+// corresponding value is empty/false, so that removing a previously set
+// value (e.g. restarting without TS_ROUTES after running with it) actually
+// clears it instead of leaving the prior EditPrefs call's state in place.
+func (s *Server) applyPrefs(ctx context.Context) error {
+	prefs := ipn.Prefs{
+		AdvertiseRoutes: s.routes,
+		RouteAll:        s.acceptRoutes,
+		AdvertiseTags:   s.tags,
+	}
+	mp := &ipn.MaskedPrefs{
+		AdvertiseRoutesSet: true,
+		RouteAllSet:        true,
+		AdvertiseTagsSet:   true,
+	}
+
+	for _, arg := range s.extraArgs {
+		key, value := splitExtraArg(arg)
+
+		apply, ok := extraArgWhitelist[key]
+		if !ok {
+			return fmt.Errorf("unsupported TS_EXTRA_ARGS flag %q", key)
+		}
+
+		if err := apply(value, &prefs, mp); err != nil {
+			return fmt.Errorf("failed to apply %q: %w", key, err)
+		}
+	}
+
+	mp.Prefs = prefs
+
+	s.logger.Debug("applying Tailscale preferences", "routes", s.routes, "accept_routes", s.acceptRoutes, "tags", s.tags, "extra_args", s.extraArgs)
+
+	if _, err := s.localClient.EditPrefs(ctx, mp); err != nil {
+		return fmt.Errorf("failed to edit preferences: %w", err)
+	}
+
+	return nil
+}
+
+// splitExtraArg splits a "--flag" or "--flag=value" TS_EXTRA_ARGS entry into
+// its flag and value.
+func splitExtraArg(arg string) (key, value string) {
+	if idx := strings.IndexByte(arg, '='); idx >= 0 {
+		return arg[:idx], arg[idx+1:]
+	}
+
+	return arg, ""
+}
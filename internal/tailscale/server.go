@@ -8,13 +8,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
 
 	"tailscale.com/client/local"
+	"tailscale.com/health"
 	"tailscale.com/ipn"
 	"tailscale.com/ipn/ipnstate"
 	"tailscale.com/tsnet"
+	"tailscale.com/wgengine/netstack"
 )
 
 // Server wraps a tsnet.Server and provides lifecycle management and access
@@ -28,15 +31,26 @@ type Server struct {
 	started       bool
 	tailscaleIP   string
 	tailscaleIPv6 string
+	healthTracker *health.Tracker
+
+	routes       []netip.Prefix
+	acceptRoutes bool
+	tags         []string
+	extraArgs    []string
 }
 
 // Config holds configuration for creating a new Server.
 type Config struct {
-	Hostname  string       // Hostname is the hostname to use for this Tailscale node.
-	StateDir  string       // StateDir is the directory where Tailscale state is stored.
-	AuthKey   string       // AuthKey is the Tailscale authentication key.
-	Logger    *slog.Logger // Logger is the logger to use for tsnet operations.
-	Ephemeral bool         // Ephemeral indicates whether this node should be ephemeral.
+	Hostname     string         // Hostname is the hostname to use for this Tailscale node.
+	StateDir     string         // StateDir is the directory where Tailscale state is stored.
+	AuthKey      string         // AuthKey is the Tailscale authentication key.
+	Logger       *slog.Logger   // Logger is the logger to use for tsnet operations.
+	Ephemeral    bool           // Ephemeral indicates whether this node should be ephemeral.
+	Routes       []netip.Prefix // Routes are subnet routes to advertise via AdvertiseRoutes.
+	AcceptRoutes bool           // AcceptRoutes indicates whether this node should accept routes advertised by other nodes.
+	Tags         []string       // Tags are ACL tags to advertise for this node.
+	Userspace    bool           // Userspace is reserved for userspace networking mode; currently a no-op.
+	ExtraArgs    []string       // ExtraArgs is a whitelisted set of additional preference flags (e.g. "--advertise-exit-node").
 }
 
 // New creates a new tsnet Server with the given configuration.
@@ -52,24 +66,34 @@ func New(cfg Config) (*Server, error) {
 
 	cfg.Logger.Debug("initializing Tailscale", "hostname", cfg.Hostname, "state_dir", cfg.StateDir, "ephemeral", cfg.Ephemeral)
 
+	// health.Tracker must be constructed and passed in explicitly; tsnet no
+	// longer falls back to a package-global tracker.
+	healthTracker := new(health.Tracker)
+
 	// Create tsnet server
 	srv := &tsnet.Server{
-		Hostname:  cfg.Hostname,
-		Dir:       cfg.StateDir,
-		AuthKey:   cfg.AuthKey,
-		Ephemeral: cfg.Ephemeral,
+		Hostname:      cfg.Hostname,
+		Dir:           cfg.StateDir,
+		AuthKey:       cfg.AuthKey,
+		Ephemeral:     cfg.Ephemeral,
+		HealthTracker: healthTracker,
 		Logf: func(format string, args ...any) {
 			cfg.Logger.Debug(fmt.Sprintf(format, args...))
 		},
 	}
 
 	return &Server{
-		server:      srv,
-		localClient: &local.Client{},
-		hostname:    cfg.Hostname,
-		stateDir:    cfg.StateDir,
-		logger:      cfg.Logger,
-		started:     false,
+		server:        srv,
+		localClient:   &local.Client{},
+		hostname:      cfg.Hostname,
+		stateDir:      cfg.StateDir,
+		logger:        cfg.Logger,
+		started:       false,
+		healthTracker: healthTracker,
+		routes:        cfg.Routes,
+		acceptRoutes:  cfg.AcceptRoutes,
+		tags:          cfg.Tags,
+		extraArgs:     cfg.ExtraArgs,
 	}, nil
 }
 
@@ -122,6 +146,10 @@ func (s *Server) Start(ctx context.Context) error {
 	s.started = true
 	s.logger.Info("Tailnet connection established", "hostname", s.hostname, "tailscale_ipv4", s.tailscaleIP, "tailscale_ipv6", s.tailscaleIPv6)
 
+	if err := s.applyPrefs(ctx); err != nil {
+		return fmt.Errorf("failed to apply preferences: %w", err)
+	}
+
 	return nil
 }
 
@@ -134,6 +162,53 @@ func (s *Server) Listen(network, address string) (net.Listener, error) {
 	return s.server.Listen(network, address)
 }
 
+// ListenPacket creates a packet-oriented (UDP) listener on the Tailscale
+// network.
+//
+// Unlike Listen, ListenPacket requires a concrete IP address rather than a
+// wildcard address, and listens on a single address family (IPv4 or IPv6)
+// at a time.
+func (s *Server) ListenPacket(network, address string) (net.PacketConn, error) {
+	if !s.started {
+		return nil, fmt.Errorf("server not started")
+	}
+
+	return s.server.ListenPacket(network, address)
+}
+
+// Dial connects to an address on the Tailscale network, by MagicDNS
+// hostname or Tailscale IP, from this node.
+func (s *Server) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if !s.started {
+		return nil, fmt.Errorf("server not started")
+	}
+
+	return s.server.Dial(ctx, network, address)
+}
+
+// Netstack returns the userspace netstack.Impl backing this server. It is
+// used to send traffic (such as ICMP echo requests) over the tailnet
+// without exec'ing external tools.
+func (s *Server) Netstack() (*netstack.Impl, error) {
+	if !s.started {
+		return nil, fmt.Errorf("server not started")
+	}
+
+	ns, ok := s.server.Sys().Netstack.GetOK()
+	if !ok {
+		return nil, fmt.Errorf("netstack not available")
+	}
+
+	return ns, nil
+}
+
+// Health returns the health.Tracker backing this node. It reports
+// tsnet-internal warnings (e.g. NAT traversal or DERP connectivity issues)
+// and can be scraped alongside skopos-level health via internal/metrics.
+func (s *Server) Health() *health.Tracker {
+	return s.healthTracker
+}
+
 // LocalClient returns the Tailscale LocalClient for API access.
 func (s *Server) LocalClient() *local.Client {
 	return s.localClient